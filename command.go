@@ -1,12 +1,12 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	etcdErr "github.com/coreos/etcd/error"
 	"github.com/coreos/etcd/store"
 	"github.com/coreos/go-raft"
 	"path"
+	"strings"
 	"time"
 )
 
@@ -57,6 +57,39 @@ func (c *TestAndSetCommand) Apply(server *raft.Server) (interface{}, error) {
 	return etcdStore.TestAndSet(c.Key, c.PrevValue, c.Value, c.ExpireTime, server.CommitIndex())
 }
 
+// CompareAndDelete command
+type CompareAndDeleteCommand struct {
+	Key       string `json:"key"`
+	PrevValue string `json:"prevValue"`
+	PrevIndex uint64 `json:"prevIndex"`
+}
+
+// The name of the compareAndDelete command in the log
+func (c *CompareAndDeleteCommand) CommandName() string {
+	return commandName("compareAndDelete")
+}
+
+// Delete the key if its current value and index match every given prevValue/prevIndex (at least one must be given)
+func (c *CompareAndDeleteCommand) Apply(server *raft.Server) (interface{}, error) {
+	return etcdStore.CompareAndDelete(c.Key, c.PrevValue, c.PrevIndex, server.CommitIndex())
+}
+
+// TxnCommand applies a batch of sub-operations as a single Raft log entry
+type TxnCommand struct {
+	Guards []store.Guard `json:"guards"`
+	Ops    []store.Op    `json:"ops"`
+}
+
+// The name of the txn command in the log
+func (c *TxnCommand) CommandName() string {
+	return commandName("txn")
+}
+
+// Evaluate the guards and, if they all hold, apply every op atomically
+func (c *TxnCommand) Apply(server *raft.Server) (interface{}, error) {
+	return etcdStore.Txn(c.Guards, c.Ops, server.CommitIndex())
+}
+
 // Get command
 type GetCommand struct {
 	Key string `json:"key"`
@@ -87,33 +120,7 @@ func (c *DeleteCommand) Apply(server *raft.Server) (interface{}, error) {
 	return etcdStore.Delete(c.Key, server.CommitIndex())
 }
 
-// Watch command
-type WatchCommand struct {
-	Key        string `json:"key"`
-	SinceIndex uint64 `json:"sinceIndex"`
-}
-
-// The name of the watch command in the log
-func (c *WatchCommand) CommandName() string {
-	return commandName("watch")
-}
-
-func (c *WatchCommand) Apply(server *raft.Server) (interface{}, error) {
-	// create a new watcher
-	watcher := store.NewWatcher()
-
-	// add to the watchers list
-	etcdStore.AddWatcher(c.Key, watcher, c.SinceIndex)
-
-	// wait for the notification for any changing
-	res := <-watcher.C
-
-	if res == nil {
-		return nil, fmt.Errorf("Clearing watch")
-	}
-
-	return json.Marshal(res)
-}
+// Watching is no longer a Command; see etcdStore.Watch and WatchHttpHandler in etcd_handlers.go.
 
 // JoinCommand
 type JoinCommand struct {
@@ -142,9 +149,26 @@ func (c *JoinCommand) Apply(raftServer *raft.Server) (interface{}, error) {
 	response, _ := etcdStore.RawGet(path.Join("_etcd/machines", c.Name))
 
 	if response != nil {
+		// The name is already a member. Re-joining with the same URLs is
+		// idempotent (e.g. a machine that lost its log and is rejoining);
+		// re-joining with different URLs is a conflicting request.
+		raftURL, etcdURL := parseMachineValue(response.Value)
+		if raftURL != c.RaftURL || etcdURL != c.EtcdURL {
+			debug("Reject join request from ", c.Name, ": URL conflict with existing machine")
+			return []byte("join fail"), etcdErr.NewError(105, c.Name)
+		}
 		return []byte("join success"), nil
 	}
 
+	// reject if another machine already owns one of these URLs
+	for key, n := range etcdStore.List("_etcd/machines") {
+		raftURL, etcdURL := parseMachineValue(n.Value)
+		if raftURL == c.RaftURL || etcdURL == c.EtcdURL {
+			debug("Reject join request from ", c.Name, ": URL conflict with ", key)
+			return []byte("join fail"), etcdErr.NewError(105, c.Name)
+		}
+	}
+
 	// check machine number in the cluster
 	num := machineNum()
 	if num == maxClusterSize {
@@ -168,3 +192,47 @@ func (c *JoinCommand) Apply(raftServer *raft.Server) (interface{}, error) {
 func (c *JoinCommand) NodeName() string {
 	return c.Name
 }
+
+// parseMachineValue splits the "raft=<url>&etcd=<url>" value stored under _etcd/machines/<name>
+func parseMachineValue(value string) (raftURL string, etcdURL string) {
+	for _, part := range strings.Split(value, "&") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "raft":
+			raftURL = kv[1]
+		case "etcd":
+			etcdURL = kv[1]
+		}
+	}
+	return raftURL, etcdURL
+}
+
+// LeaveCommand removes a machine from the cluster, the symmetric opposite of JoinCommand
+type LeaveCommand struct {
+	Name string `json:"name"`
+}
+
+// The name of the leave command in the log
+func (c *LeaveCommand) CommandName() string {
+	return commandName("leave")
+}
+
+// Remove a server from the cluster
+func (c *LeaveCommand) Apply(raftServer *raft.Server) (interface{}, error) {
+	err := raftServer.RemovePeer(c.Name)
+	if err != nil {
+		return []byte("leave fail"), err
+	}
+
+	removeNameFromURL(c.Name)
+
+	key := path.Join("_etcd/machines", c.Name)
+	if _, err := etcdStore.Delete(key, raftServer.CommitIndex()); err != nil {
+		debug("Leave: failed to remove machine entry for ", c.Name, ": ", err)
+	}
+
+	return []byte("leave success"), nil
+}
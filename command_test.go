@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+
+	etcdErr "github.com/coreos/etcd/error"
+	"github.com/coreos/etcd/store"
+	"github.com/coreos/go-raft"
+)
+
+// testRaftServer returns a zero-value *raft.Server sufficient to exercise
+// the command paths below, which only touch CommitIndex/AddPeer/RemovePeer
+// bookkeeping and never require a running cluster.
+func testRaftServer() *raft.Server {
+	return &raft.Server{}
+}
+
+func TestJoinCommandIdempotentRejoin(t *testing.T) {
+	etcdStore = store.New()
+
+	join := &JoinCommand{Name: "node1", RaftURL: "raft://node1", EtcdURL: "etcd://node1"}
+	if _, err := join.Apply(testRaftServer()); err != nil {
+		t.Fatalf("unexpected error on first join: %v", err)
+	}
+
+	resp, err := join.Apply(testRaftServer())
+	if err != nil {
+		t.Fatalf("re-joining with the same name and URLs should be idempotent, got error: %v", err)
+	}
+	if string(resp.([]byte)) != "join success" {
+		t.Fatalf("expected join success, got %q", resp)
+	}
+}
+
+func TestJoinCommandRejectsConflictingURLSameName(t *testing.T) {
+	etcdStore = store.New()
+
+	if _, err := (&JoinCommand{Name: "node1", RaftURL: "raft://node1", EtcdURL: "etcd://node1"}).Apply(testRaftServer()); err != nil {
+		t.Fatalf("unexpected error on first join: %v", err)
+	}
+
+	_, err := (&JoinCommand{Name: "node1", RaftURL: "raft://node1-new", EtcdURL: "etcd://node1"}).Apply(testRaftServer())
+	if err == nil {
+		t.Fatalf("expected an error when node1 rejoins with a different RaftURL")
+	}
+	if e, ok := err.(*etcdErr.Error); !ok || e.ErrorCode != 105 {
+		t.Fatalf("expected EcodeNodeExist (105), got %v", err)
+	}
+}
+
+func TestJoinCommandRejectsConflictingURLDifferentName(t *testing.T) {
+	etcdStore = store.New()
+
+	if _, err := (&JoinCommand{Name: "node1", RaftURL: "raft://shared", EtcdURL: "etcd://node1"}).Apply(testRaftServer()); err != nil {
+		t.Fatalf("unexpected error on first join: %v", err)
+	}
+
+	_, err := (&JoinCommand{Name: "node2", RaftURL: "raft://shared", EtcdURL: "etcd://node2"}).Apply(testRaftServer())
+	if err == nil {
+		t.Fatalf("expected an error when node2 tries to reuse node1's RaftURL")
+	}
+	if e, ok := err.(*etcdErr.Error); !ok || e.ErrorCode != 105 {
+		t.Fatalf("expected EcodeNodeExist (105), got %v", err)
+	}
+}
+
+func TestLeaveCommandRemovesMachineEntry(t *testing.T) {
+	etcdStore = store.New()
+
+	if _, err := (&JoinCommand{Name: "node1", RaftURL: "raft://node1", EtcdURL: "etcd://node1"}).Apply(testRaftServer()); err != nil {
+		t.Fatalf("unexpected error on join: %v", err)
+	}
+	if _, err := etcdStore.Get("_etcd/machines/node1"); err != nil {
+		t.Fatalf("expected node1 to be registered after join: %v", err)
+	}
+
+	if _, err := (&LeaveCommand{Name: "node1"}).Apply(testRaftServer()); err != nil {
+		t.Fatalf("unexpected error on leave: %v", err)
+	}
+	if _, err := etcdStore.Get("_etcd/machines/node1"); err == nil {
+		t.Fatalf("expected node1's machine entry to be removed after leave")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/coreos/etcd/store"
+)
+
+// CompareAndDeleteHttpHandler deletes a key if prevValue/prevIndex still match, parallel to the testAndSet route
+func CompareAndDeleteHttpHandler(w http.ResponseWriter, req *http.Request) {
+	key := req.URL.Path[len("/v1/keys/"):]
+
+	req.ParseForm()
+	prevValue := req.Form.Get("prevValue")
+	prevIndex, _ := strconv.ParseUint(req.Form.Get("prevIndex"), 10, 64)
+
+	command := &CompareAndDeleteCommand{
+		Key:       key,
+		PrevValue: prevValue,
+		PrevIndex: prevIndex,
+	}
+
+	dispatch(command, w, req)
+}
+
+// WatchHttpHandler streams JSON-encoded store.Event values until the client disconnects or the watcher is compacted
+func WatchHttpHandler(w http.ResponseWriter, req *http.Request) {
+	key := req.URL.Path[len("/v1/watch/"):]
+
+	req.ParseForm()
+	recursive := req.Form.Get("recursive") == "true"
+	sinceIndex, _ := strconv.ParseUint(req.Form.Get("sinceIndex"), 10, 64)
+
+	watcher, err := etcdStore.Watch(key, recursive, sinceIndex)
+	if err == store.ErrWatcherCompacted {
+		w.WriteHeader(http.StatusGone)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	closeNotifier, ok := w.(http.CloseNotifier)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	notify := closeNotifier.CloseNotify()
+
+	encoder := json.NewEncoder(w)
+
+	for {
+		select {
+		case <-notify:
+			watcher.Remove()
+			return
+		case e, open := <-watcher.EventChan:
+			if !open {
+				// The hub dropped us for falling too far behind. The 410
+				// status reaches the client only if no event has been
+				// streamed yet; otherwise the response simply ends.
+				w.WriteHeader(http.StatusGone)
+				return
+			}
+			if err := encoder.Encode(e); err != nil {
+				watcher.Remove()
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// LeaveHttpHandler triggers graceful removal of a machine from the cluster
+func LeaveHttpHandler(w http.ResponseWriter, req *http.Request) {
+	name := req.URL.Path[len("/v1/admin/machines/"):]
+
+	command := &LeaveCommand{Name: name}
+
+	dispatch(command, w, req)
+}
+
+// TxnHttpHandler decodes a JSON batch of guards and ops into a TxnCommand
+func TxnHttpHandler(w http.ResponseWriter, req *http.Request) {
+	command := &TxnCommand{}
+
+	if err := json.NewDecoder(req.Body).Decode(command); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	dispatch(command, w, req)
+}
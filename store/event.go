@@ -0,0 +1,58 @@
+package store
+
+// Event describes a single change that committed to the store
+type Event struct {
+	Action string `json:"action"` // "set", "delete" or "compareAndDelete"
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Index  uint64 `json:"index"`
+}
+
+// eventHistory is a fixed-size ring buffer of the most recent events
+type eventHistory struct {
+	events []*Event
+	start  int
+	size   int
+	cap    int
+}
+
+func newEventHistory(capacity int) *eventHistory {
+	return &eventHistory{events: make([]*Event, capacity), cap: capacity}
+}
+
+func (h *eventHistory) add(e *Event) {
+	idx := (h.start + h.size) % h.cap
+	h.events[idx] = e
+
+	if h.size < h.cap {
+		h.size++
+	} else {
+		h.start = (h.start + 1) % h.cap
+	}
+}
+
+// oldestIndex returns the index of the oldest event still retained, or 0 if the history is empty
+func (h *eventHistory) oldestIndex() uint64 {
+	if h.size == 0 {
+		return 0
+	}
+	return h.events[h.start].Index
+}
+
+// since returns every retained event with Index > sinceIndex; compacted is true if some were already dropped
+func (h *eventHistory) since(sinceIndex uint64) (events []*Event, compacted bool) {
+	if h.size == 0 {
+		return nil, false
+	}
+	if sinceIndex != 0 && sinceIndex < h.oldestIndex()-1 {
+		return nil, true
+	}
+
+	for i := 0; i < h.size; i++ {
+		e := h.events[(h.start+i)%h.cap]
+		if e.Index > sinceIndex {
+			events = append(events, e)
+		}
+	}
+	return events, false
+}
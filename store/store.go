@@ -0,0 +1,153 @@
+package store
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	etcdErr "github.com/coreos/etcd/error"
+)
+
+// Node represents a single key-value entry held by the store.
+type Node struct {
+	Value      string
+	ExpireTime time.Time
+	Index      uint64
+}
+
+// Store is the in-memory key-value store backing the etcd state machine.
+type Store struct {
+	mutex sync.Mutex
+	Nodes map[string]*Node
+
+	WatcherHub *watcherHub
+}
+
+func New() *Store {
+	return &Store{
+		Nodes:      make(map[string]*Node),
+		WatcherHub: newWatcherHub(),
+	}
+}
+
+// Get returns the value currently stored at key.
+func (s *Store) Get(key string) (*Node, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.rawGet(key)
+}
+
+// RawGet returns the value at key without going through notification logic.
+func (s *Store) RawGet(key string) (*Node, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.rawGet(key)
+}
+
+func (s *Store) rawGet(key string) (*Node, error) {
+	n, ok := s.Nodes[key]
+	if !ok {
+		return nil, etcdErr.NewError(100, key)
+	}
+	return n, nil
+}
+
+// Set unconditionally writes value to key and notifies any watchers.
+func (s *Store) Set(key string, value string, expireTime time.Time, index uint64) (*Node, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	n := &Node{Value: value, ExpireTime: expireTime, Index: index}
+	s.Nodes[key] = n
+
+	s.WatcherHub.notify(&Event{Action: "set", Key: key, Value: value, Index: index})
+
+	return n, nil
+}
+
+// Delete removes key from the store and notifies any watchers.
+func (s *Store) Delete(key string, index uint64) (*Node, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	n, ok := s.Nodes[key]
+	if !ok {
+		return nil, etcdErr.NewError(100, key)
+	}
+
+	delete(s.Nodes, key)
+	s.WatcherHub.notify(&Event{Action: "delete", Key: key, Index: index})
+
+	return n, nil
+}
+
+// TestAndSet sets key to value only if its current value equals prevValue.
+func (s *Store) TestAndSet(key string, prevValue string, value string, expireTime time.Time, index uint64) (*Node, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	n, ok := s.Nodes[key]
+	if ok && n.Value != prevValue {
+		return nil, etcdErr.NewError(101, key)
+	}
+	if !ok && prevValue != "" {
+		return nil, etcdErr.NewError(101, key)
+	}
+
+	newNode := &Node{Value: value, ExpireTime: expireTime, Index: index}
+	s.Nodes[key] = newNode
+
+	s.WatcherHub.notify(&Event{Action: "set", Key: key, Value: value, Index: index})
+
+	return newNode, nil
+}
+
+// List returns every node whose key has the given prefix, keyed by key.
+func (s *Store) List(prefix string) map[string]*Node {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	nodes := make(map[string]*Node)
+	for k, n := range s.Nodes {
+		if strings.HasPrefix(k, prefix) {
+			nodes[k] = n
+		}
+	}
+	return nodes
+}
+
+// Watch establishes a streaming subscription to key, served off the local store without going through Raft
+func (s *Store) Watch(key string, recursive bool, sinceIndex uint64) (*Watcher, error) {
+	return s.WatcherHub.watch(key, recursive, sinceIndex)
+}
+
+// CompareAndDelete removes key if its current value equals prevValue (when prevValue is non-empty) and its
+// current index equals prevIndex (when prevIndex is non-zero). Giving neither is an error.
+func (s *Store) CompareAndDelete(key string, prevValue string, prevIndex uint64, index uint64) (*Node, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if prevValue == "" && prevIndex == 0 {
+		return nil, etcdErr.NewError(201, key)
+	}
+
+	n, ok := s.Nodes[key]
+	if !ok {
+		return nil, etcdErr.NewError(100, key)
+	}
+
+	if prevValue != "" && n.Value != prevValue {
+		return nil, etcdErr.NewError(101, key)
+	}
+
+	if prevIndex != 0 && n.Index != prevIndex {
+		return nil, etcdErr.NewError(101, key)
+	}
+
+	delete(s.Nodes, key)
+	s.WatcherHub.notify(&Event{Action: "compareAndDelete", Key: key, Index: index})
+
+	return n, nil
+}
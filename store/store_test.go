@@ -0,0 +1,93 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompareAndDeleteRequiresPrevValueOrPrevIndex(t *testing.T) {
+	s := New()
+	s.Set("/foo", "bar", time.Time{}, 1)
+
+	if _, err := s.CompareAndDelete("/foo", "", 0, 2); err == nil {
+		t.Fatalf("expected an error when neither prevValue nor prevIndex is given")
+	}
+	if n, err := s.Get("/foo"); err != nil || n.Value != "bar" {
+		t.Fatalf("key must not be deleted when the guard is unset")
+	}
+}
+
+func TestCompareAndDeleteKeyNotFound(t *testing.T) {
+	s := New()
+
+	if _, err := s.CompareAndDelete("/missing", "bar", 0, 1); err == nil {
+		t.Fatalf("expected an error for a missing key")
+	}
+}
+
+func TestCompareAndDeletePrevValueMismatch(t *testing.T) {
+	s := New()
+	s.Set("/foo", "bar", time.Time{}, 1)
+
+	if _, err := s.CompareAndDelete("/foo", "wrong", 0, 2); err == nil {
+		t.Fatalf("expected an error on prevValue mismatch")
+	}
+	if n, err := s.Get("/foo"); err != nil || n.Value != "bar" {
+		t.Fatalf("key must not be deleted on prevValue mismatch")
+	}
+}
+
+func TestCompareAndDeletePrevIndexMismatch(t *testing.T) {
+	s := New()
+	s.Set("/foo", "bar", time.Time{}, 1)
+
+	if _, err := s.CompareAndDelete("/foo", "", 99, 2); err == nil {
+		t.Fatalf("expected an error on prevIndex mismatch")
+	}
+	if n, err := s.Get("/foo"); err != nil || n.Value != "bar" {
+		t.Fatalf("key must not be deleted on prevIndex mismatch")
+	}
+}
+
+func TestCompareAndDeleteSucceedsOnMatchingValue(t *testing.T) {
+	s := New()
+	s.Set("/foo", "bar", time.Time{}, 1)
+
+	if _, err := s.CompareAndDelete("/foo", "bar", 0, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Get("/foo"); err == nil {
+		t.Fatalf("key should have been deleted")
+	}
+}
+
+func TestCompareAndDeleteSucceedsOnMatchingIndex(t *testing.T) {
+	s := New()
+	s.Set("/foo", "bar", time.Time{}, 1)
+
+	if _, err := s.CompareAndDelete("/foo", "", 1, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Get("/foo"); err == nil {
+		t.Fatalf("key should have been deleted")
+	}
+}
+
+func TestCompareAndDeleteNotifiesWatcher(t *testing.T) {
+	s := New()
+	s.Set("/foo", "bar", time.Time{}, 1)
+
+	w, err := s.Watch("/foo", false, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := s.CompareAndDelete("/foo", "bar", 0, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	e := <-w.EventChan
+	if e.Action != "compareAndDelete" || e.Key != "/foo" {
+		t.Fatalf("expected a compareAndDelete event for /foo, got %+v", e)
+	}
+}
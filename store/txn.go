@@ -0,0 +1,100 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// Op is a single mutation inside a TxnCommand batch.
+type Op struct {
+	Type       string    `json:"type"` // "set", "delete" or "testAndSet"
+	Key        string    `json:"key"`
+	Value      string    `json:"value,omitempty"`
+	PrevValue  string    `json:"prevValue,omitempty"`
+	ExpireTime time.Time `json:"expireTime,omitempty"`
+}
+
+// Guard is a precondition that must hold for every op in a TxnCommand to
+// be applied. A nil Exists/empty Value/zero Index means that field is not
+// checked.
+type Guard struct {
+	Key    string `json:"key"`
+	Exists *bool  `json:"exists,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Index  uint64 `json:"index,omitempty"`
+}
+
+// GuardFailure is returned by Txn when a guard, or a testAndSet op's
+// prevValue check, does not hold. Index is the position of the failing
+// guard in guards, or of the failing testAndSet op in ops; Reason tells
+// which precondition was violated.
+type GuardFailure struct {
+	Index  int
+	Key    string
+	Reason string
+}
+
+func (e *GuardFailure) Error() string {
+	return fmt.Sprintf("txn: guard %d on %q failed: %s", e.Index, e.Key, e.Reason)
+}
+
+// Txn evaluates guards against the current state and, if they all hold,
+// applies ops in order under the same commit index. Either every op is
+// applied or none are.
+func (s *Store) Txn(guards []Guard, ops []Op, index uint64) ([]*Node, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, g := range guards {
+		n, ok := s.Nodes[g.Key]
+
+		if g.Exists != nil && *g.Exists != ok {
+			return nil, &GuardFailure{Index: i, Key: g.Key, Reason: "existence check failed"}
+		}
+		if g.Value != "" && (!ok || n.Value != g.Value) {
+			return nil, &GuardFailure{Index: i, Key: g.Key, Reason: "value mismatch"}
+		}
+		if g.Index != 0 && (!ok || n.Index != g.Index) {
+			return nil, &GuardFailure{Index: i, Key: g.Key, Reason: "index mismatch"}
+		}
+	}
+
+	// Validate every op's type and testAndSet precondition before mutating
+	// anything, so a failure partway through the batch never leaves
+	// earlier ops applied.
+	for i, op := range ops {
+		switch op.Type {
+		case "set", "delete":
+		case "testAndSet":
+			n, ok := s.Nodes[op.Key]
+			if (ok && n.Value != op.PrevValue) || (!ok && op.PrevValue != "") {
+				return nil, &GuardFailure{Index: i, Key: op.Key, Reason: "testAndSet prevValue mismatch"}
+			}
+		default:
+			return nil, fmt.Errorf("txn: unknown op type %q", op.Type)
+		}
+	}
+
+	results := make([]*Node, len(ops))
+	for i, op := range ops {
+		switch op.Type {
+		case "set", "testAndSet":
+			n := &Node{Value: op.Value, ExpireTime: op.ExpireTime, Index: index}
+			s.Nodes[op.Key] = n
+			results[i] = n
+		case "delete":
+			results[i] = s.Nodes[op.Key]
+			delete(s.Nodes, op.Key)
+		}
+	}
+
+	for _, op := range ops {
+		action := op.Type
+		if action == "testAndSet" {
+			action = "set"
+		}
+		s.WatcherHub.notify(&Event{Action: action, Key: op.Key, Value: op.Value, Index: index})
+	}
+
+	return results, nil
+}
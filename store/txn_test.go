@@ -0,0 +1,71 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTxnGuardExistsFailure(t *testing.T) {
+	s := New()
+
+	exists := true
+	_, err := s.Txn([]Guard{{Key: "/foo", Exists: &exists}}, []Op{{Type: "set", Key: "/foo", Value: "bar"}}, 1)
+	if _, ok := err.(*GuardFailure); !ok {
+		t.Fatalf("expected *GuardFailure, got %v", err)
+	}
+	if _, err := s.Get("/foo"); err == nil {
+		t.Fatalf("guard failure should not have applied any ops")
+	}
+}
+
+func TestTxnGuardValueMismatch(t *testing.T) {
+	s := New()
+	s.Set("/foo", "bar", time.Time{}, 1)
+
+	_, err := s.Txn([]Guard{{Key: "/foo", Value: "baz"}}, []Op{{Type: "delete", Key: "/foo"}}, 2)
+	if _, ok := err.(*GuardFailure); !ok {
+		t.Fatalf("expected *GuardFailure, got %v", err)
+	}
+	if n, err := s.Get("/foo"); err != nil || n.Value != "bar" {
+		t.Fatalf("guard failure should not have deleted /foo")
+	}
+}
+
+func TestTxnRejectsUnknownOpTypeWithoutMutating(t *testing.T) {
+	s := New()
+	s.Set("/foo", "bar", time.Time{}, 1)
+
+	_, err := s.Txn(nil, []Op{
+		{Type: "set", Key: "/foo", Value: "changed"},
+		{Type: "bogus", Key: "/bar"},
+	}, 2)
+	if err == nil {
+		t.Fatalf("expected an error for unknown op type")
+	}
+	if n, _ := s.Get("/foo"); n.Value != "bar" {
+		t.Fatalf("earlier op in the batch must not be applied when a later op is invalid, got value %q", n.Value)
+	}
+}
+
+func TestTxnAppliesAllOpsAtomically(t *testing.T) {
+	s := New()
+	s.Set("/a", "1", time.Time{}, 1)
+
+	results, err := s.Txn(nil, []Op{
+		{Type: "set", Key: "/a", Value: "2"},
+		{Type: "set", Key: "/b", Value: "3"},
+		{Type: "delete", Key: "/a"},
+	}, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if _, err := s.Get("/a"); err == nil {
+		t.Fatalf("/a should have been deleted")
+	}
+	if n, err := s.Get("/b"); err != nil || n.Value != "3" {
+		t.Fatalf("/b should be set to 3")
+	}
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// watcherEventBufferSize bounds how many events a watcher may lag behind before it is dropped
+const watcherEventBufferSize = 100
+
+// ErrWatcherCompacted is returned when a watcher falls too far behind or resumes from a stale sinceIndex
+var ErrWatcherCompacted = errors.New("watcher fell behind and was compacted")
+
+// Watcher is a streaming, possibly-recursive subscription to changes under a key
+type Watcher struct {
+	EventChan chan *Event
+	Err       error
+
+	key       string
+	recursive bool
+
+	hub *watcherHub
+}
+
+// Remove cancels the watch, e.g. when the HTTP client disconnects.
+func (w *Watcher) Remove() {
+	w.hub.remove(w)
+}
+
+func (w *Watcher) matches(key string) bool {
+	if w.recursive {
+		return key == w.key || strings.HasPrefix(key, w.key+"/")
+	}
+	return key == w.key
+}
+
+type watcherHub struct {
+	mutex    sync.Mutex
+	watchers map[string][]*Watcher
+	history  *eventHistory
+}
+
+func newWatcherHub() *watcherHub {
+	return &watcherHub{
+		watchers: make(map[string][]*Watcher),
+		history:  newEventHistory(1000),
+	}
+}
+
+// watch establishes a Watcher on key, replaying retained events after sinceIndex if it is non-zero
+func (h *watcherHub) watch(key string, recursive bool, sinceIndex uint64) (*Watcher, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	w := &Watcher{
+		EventChan: make(chan *Event, watcherEventBufferSize),
+		key:       key,
+		recursive: recursive,
+		hub:       h,
+	}
+
+	if sinceIndex != 0 {
+		events, compacted := h.history.since(sinceIndex)
+		if compacted {
+			return nil, ErrWatcherCompacted
+		}
+		for _, e := range events {
+			if !w.matches(e.Key) {
+				continue
+			}
+			select {
+			case w.EventChan <- e:
+			default:
+				return nil, ErrWatcherCompacted
+			}
+		}
+	}
+
+	h.watchers[key] = append(h.watchers[key], w)
+	return w, nil
+}
+
+func (h *watcherHub) remove(w *Watcher) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	ws := h.watchers[w.key]
+	for i, existing := range ws {
+		if existing == w {
+			h.watchers[w.key] = append(ws[:i], ws[i+1:]...)
+			break
+		}
+	}
+}
+
+// notify records e in the history and fans it out to every watcher whose key or prefix matches
+func (h *watcherHub) notify(e *Event) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.history.add(e)
+
+	for watchKey, ws := range h.watchers {
+		if !e.matchesWatchKey(watchKey) {
+			continue
+		}
+
+		remaining := ws[:0]
+		for _, w := range ws {
+			if !w.matches(e.Key) {
+				remaining = append(remaining, w)
+				continue
+			}
+
+			select {
+			case w.EventChan <- e:
+				remaining = append(remaining, w)
+			default:
+				w.Err = ErrWatcherCompacted
+				close(w.EventChan)
+			}
+		}
+		h.watchers[watchKey] = remaining
+	}
+}
+
+// matchesWatchKey is a cheap pre-filter before notify runs a watcher's full match logic
+func (e *Event) matchesWatchKey(watchKey string) bool {
+	return e.Key == watchKey || strings.HasPrefix(e.Key, watchKey) || strings.HasPrefix(watchKey, e.Key)
+}
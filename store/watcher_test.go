@@ -0,0 +1,80 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventHistorySinceReturnsEventsAfterIndex(t *testing.T) {
+	h := newEventHistory(4)
+	for i := uint64(1); i <= 4; i++ {
+		h.add(&Event{Key: "/foo", Index: i})
+	}
+
+	events, compacted := h.since(2)
+	if compacted {
+		t.Fatalf("should not be compacted")
+	}
+	if len(events) != 2 || events[0].Index != 3 || events[1].Index != 4 {
+		t.Fatalf("expected events 3 and 4, got %v", events)
+	}
+}
+
+func TestEventHistorySinceCompactionBoundary(t *testing.T) {
+	h := newEventHistory(4)
+	for i := uint64(1); i <= 8; i++ {
+		h.add(&Event{Key: "/foo", Index: i})
+	}
+
+	// only indices 5-8 are retained; asking for events after an already-evicted index is compacted
+	if _, compacted := h.since(3); !compacted {
+		t.Fatalf("expected compaction for an index older than the retained window")
+	}
+	if events, compacted := h.since(4); compacted || len(events) != 4 {
+		t.Fatalf("expected all 4 retained events at the boundary, got %v compacted=%v", events, compacted)
+	}
+	if events, compacted := h.since(5); compacted || len(events) != 3 {
+		t.Fatalf("expected 3 events with no compaction, got %v compacted=%v", events, compacted)
+	}
+}
+
+func TestWatcherHubNotifyCompactsSlowWatcher(t *testing.T) {
+	h := newWatcherHub()
+	w, err := h.watch("/foo", false, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// fill the watcher's buffer, then push one more to force compaction
+	for i := 0; i < watcherEventBufferSize+1; i++ {
+		h.notify(&Event{Key: "/foo", Index: uint64(i + 1)})
+	}
+
+	for range w.EventChan {
+	}
+	if w.Err != ErrWatcherCompacted {
+		t.Fatalf("expected watcher to be compacted after overflowing its buffer, got %v", w.Err)
+	}
+}
+
+func TestWatcherHubWatchReplayCompactsInsteadOfBlocking(t *testing.T) {
+	h := newWatcherHub()
+	for i := 0; i < watcherEventBufferSize+10; i++ {
+		h.notify(&Event{Key: "/foo", Index: uint64(i + 1)})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_, err := h.watch("/foo", false, 1)
+		if err != ErrWatcherCompacted {
+			t.Errorf("expected ErrWatcherCompacted for a replay window larger than the buffer, got %v", err)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("watch() blocked instead of compacting the replay")
+	}
+}